@@ -0,0 +1,92 @@
+//go:build integration
+// +build integration
+
+package test
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/linkerd/linkerd-smi/testutil"
+)
+
+var (
+	TestHelper *testutil.TestHelper
+
+	errorLogRegexpFlag = flag.String("error-log-regexp", `(?i)error|fatal|level=error`,
+		"regexp used to flag suspicious lines in control-plane container logs")
+	errorLogRegexp *regexp.Regexp
+)
+
+// expectedDeployments describes the SMI control-plane deployments installed
+// by `linkerd smi install`, and the containers (including sidecars injected
+// by linkerd2) each of their pods is expected to run.
+var expectedDeployments = map[string]testutil.DeploySpec{
+	"smi-adaptor": {
+		Replicas:   1,
+		Containers: []string{"smi-adaptor", "linkerd-proxy", "linkerd-init"},
+	},
+}
+
+func TestMain(m *testing.M) {
+	// NewTestHelper registers and parses its own flags (-integration-tests,
+	// -linkerd, -k8s-context, etc.), so it must run before we read
+	// errorLogRegexpFlag, and we must not call flag.Parse ourselves first:
+	// doing so would fail on those not-yet-registered flags.
+	TestHelper = testutil.NewTestHelper()
+	errorLogRegexp = regexp.MustCompile(*errorLogRegexpFlag)
+	os.Exit(m.Run())
+}
+
+// TestSMIControlPlaneHealth installs the SMI control plane and asserts that
+// every expected deployment came up healthy: the declared replica count is
+// ready, every expected container (including injected sidecars) is present,
+// no container has restarted, and no container has logged an error.
+func TestSMIControlPlaneHealth(t *testing.T) {
+	ctx := context.Background()
+
+	out, err := TestHelper.LinkerdSMIRun(TestHelper.GetLinkerdNamespace(), "install")
+	if err != nil {
+		t.Fatalf("linkerd smi install failed: %s", err)
+	}
+
+	if _, err := TestHelper.KubectlApply(out, TestHelper.GetLinkerdNamespace()); err != nil {
+		t.Fatalf("kubectl apply of SMI control plane failed: %s", err)
+	}
+
+	for deployName, spec := range expectedDeployments {
+		deployName, spec := deployName, spec
+		t.Run(deployName, func(t *testing.T) {
+			err := TestHelper.RetryFor(90*time.Second, func() error {
+				return TestHelper.CheckPods(ctx, TestHelper.GetLinkerdNamespace(), deployName, spec)
+			})
+			if err != nil {
+				t.Fatalf("deployment %s did not become healthy: %s", deployName, err)
+			}
+
+			pods, err := TestHelper.GetPods(ctx, TestHelper.GetLinkerdNamespace(), fmt.Sprintf("app=%s", deployName))
+			if err != nil {
+				t.Fatalf("error fetching pods for %s: %s", deployName, err)
+			}
+
+			for _, pod := range pods {
+				for _, container := range spec.Containers {
+					logs, err := TestHelper.GetContainerLogs(ctx, pod, container)
+					if err != nil {
+						t.Fatalf("error fetching logs for %s/%s: %s", pod.Name, container, err)
+					}
+
+					if errorLogRegexp.MatchString(logs) {
+						t.Errorf("container %s of pod %s logged a suspicious line matching %q",
+							container, pod.Name, errorLogRegexp.String())
+					}
+				}
+			}
+		})
+	}
+}
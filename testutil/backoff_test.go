@@ -0,0 +1,73 @@
+package testutil
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func assertJittered(t *testing.T, got, base time.Duration) {
+	t.Helper()
+	min := time.Duration(float64(base) * 0.8)
+	max := time.Duration(float64(base) * 1.2)
+	if got < min || got > max {
+		t.Fatalf("expected wait within +/-20%% of %s, got %s", base, got)
+	}
+}
+
+func TestExponentialBackoffNextBackOff(t *testing.T) {
+	b := &ExponentialBackoff{Initial: 100 * time.Millisecond, Multiplier: 2, Max: 500 * time.Millisecond}
+
+	assertJittered(t, b.NextBackOff(), 100*time.Millisecond)
+	assertJittered(t, b.NextBackOff(), 200*time.Millisecond)
+	assertJittered(t, b.NextBackOff(), 400*time.Millisecond)
+
+	// Once the backoff would exceed Max, it should stay capped there.
+	for i := 0; i < 5; i++ {
+		assertJittered(t, b.NextBackOff(), 500*time.Millisecond)
+	}
+}
+
+func TestExponentialBackoffReset(t *testing.T) {
+	b := &ExponentialBackoff{Initial: 100 * time.Millisecond, Multiplier: 2, Max: 500 * time.Millisecond}
+
+	b.NextBackOff()
+	b.NextBackOff()
+
+	b.Reset()
+
+	assertJittered(t, b.NextBackOff(), b.Initial)
+}
+
+func TestRetryForBackoffSucceedsAfterRetries(t *testing.T) {
+	h := &TestHelper{}
+	b := &ExponentialBackoff{Initial: time.Millisecond, Multiplier: 2, Max: 10 * time.Millisecond}
+
+	attempts := 0
+	err := h.RetryForBackoff(time.Second, b, func() error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("not ready yet")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected eventual success, got error: %s", err)
+	}
+	if attempts != 3 {
+		t.Fatalf("expected 3 attempts, got %d", attempts)
+	}
+}
+
+func TestRetryForBackoffTimesOut(t *testing.T) {
+	h := &TestHelper{}
+	b := &ExponentialBackoff{Initial: 5 * time.Millisecond, Multiplier: 2, Max: 5 * time.Millisecond}
+	wantErr := errors.New("always fails")
+
+	err := h.RetryForBackoff(20*time.Millisecond, b, func() error {
+		return wantErr
+	})
+	if err != wantErr {
+		t.Fatalf("expected %s, got %s", wantErr, err)
+	}
+}
@@ -0,0 +1,104 @@
+package testutil
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestFakeRunnerScriptedResult(t *testing.T) {
+	runner := NewFakeRunner()
+	runner.Scripted["linkerd smi install"] = FakeResult{Result: Result{Stdout: "installed\n"}}
+
+	result, err := runner.Run(context.Background(), "linkerd", "smi", "install")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if result.Stdout != "installed\n" {
+		t.Fatalf("expected scripted stdout, got %q", result.Stdout)
+	}
+	if len(runner.Invocations) != 1 {
+		t.Fatalf("expected 1 recorded invocation, got %d", len(runner.Invocations))
+	}
+}
+
+func TestFakeRunnerLongestPrefixWins(t *testing.T) {
+	runner := NewFakeRunner()
+	runner.Scripted["linkerd"] = FakeResult{Result: Result{Stdout: "generic"}}
+	runner.Scripted["linkerd smi install"] = FakeResult{Result: Result{Stdout: "specific"}}
+
+	result, err := runner.Run(context.Background(), "linkerd", "smi", "install")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if result.Stdout != "specific" {
+		t.Fatalf("expected the longest matching prefix to win, got %q", result.Stdout)
+	}
+}
+
+func TestFakeRunnerNoScriptedResult(t *testing.T) {
+	runner := NewFakeRunner()
+
+	if _, err := runner.Run(context.Background(), "linkerd", "smi", "install"); err == nil {
+		t.Fatal("expected an error for an unscripted invocation")
+	}
+}
+
+// blockingRunner blocks until its context is cancelled, to exercise
+// TimeoutRunner without shelling out to a real process.
+type blockingRunner struct{}
+
+func (r blockingRunner) Run(ctx context.Context, name string, args ...string) (Result, error) {
+	return r.RunWithInput(ctx, nil, name, args...)
+}
+
+func (blockingRunner) RunWithInput(ctx context.Context, stdin io.Reader, name string, args ...string) (Result, error) {
+	<-ctx.Done()
+	return Result{}, ctx.Err()
+}
+
+func TestTimeoutRunnerBoundsBlockedCommand(t *testing.T) {
+	runner := TimeoutRunner{Runner: blockingRunner{}, Timeout: 10 * time.Millisecond}
+
+	start := time.Now()
+	_, err := runner.Run(context.Background(), "linkerd", "smi", "install")
+	if err == nil {
+		t.Fatal("expected a timeout error")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected TimeoutRunner to bound the call, took %s", elapsed)
+	}
+}
+
+func TestLinkerdSMIRunUsesInjectedRunner(t *testing.T) {
+	runner := NewFakeRunner()
+	runner.Scripted["linkerd smi --linkerd-namespace linkerd"] = FakeResult{Result: Result{Stdout: "installed\n"}}
+
+	h := &TestHelper{linkerd: "linkerd"}
+	h.SetCommandRunner(runner)
+
+	out, err := h.LinkerdSMIRun("linkerd", "install")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if out != "installed\n" {
+		t.Fatalf("expected scripted stdout, got %q", out)
+	}
+}
+
+func TestLinkerdRunUsesInjectedRunner(t *testing.T) {
+	runner := NewFakeRunner()
+	runner.Scripted["linkerd --linkerd-namespace linkerd"] = FakeResult{Result: Result{Stdout: "ok\n"}}
+
+	h := &TestHelper{linkerd: "linkerd", namespace: "linkerd"}
+	h.SetCommandRunner(runner)
+
+	out, err := h.LinkerdRun("version")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if out != "ok\n" {
+		t.Fatalf("expected scripted stdout, got %q", out)
+	}
+}
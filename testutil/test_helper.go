@@ -5,17 +5,22 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"io"
+	"math/rand"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"testing"
 	"time"
 
 	serviceprofile "github.com/linkerd/linkerd2/controller/gen/apis/serviceprofile/v1alpha2"
 	spclientset "github.com/linkerd/linkerd2/controller/gen/client/clientset/versioned"
 	"github.com/linkerd/linkerd2/testutil"
 	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
@@ -25,8 +30,25 @@ type TestHelper struct {
 	namespace  string
 	k8sContext string
 	spClient   *spclientset.Clientset
+	kubeClient kubernetes.Interface
+	runner     CommandRunner
 
 	testutil.KubernetesHelper
+
+	// targetNamespace and targetK8sContext identify the second cluster used
+	// by multicluster TrafficSplit tests. They are empty unless -k8s-context-target
+	// is provided.
+	targetNamespace  string
+	targetK8sContext string
+	targetSPClient   *spclientset.Clientset
+	targetK8s        *testutil.KubernetesHelper
+}
+
+// DeploySpec describes the expected shape of a Kubernetes deployment, for use
+// with TestHelper.CheckPods.
+type DeploySpec struct {
+	Replicas   int
+	Containers []string
 }
 
 // NewTestHelper creates a new instance of TestHelper for the current test run.
@@ -40,6 +62,10 @@ func NewTestHelper() *TestHelper {
 	k8sContext := flag.String("k8s-context", "", "kubernetes context associated with the test cluster")
 	linkerd := flag.String("linkerd", "", "path to the linkerd binary to test")
 	namespace := flag.String("linkerd-namespace", "linkerd", "the namespace where linkerd is installed")
+	k8sContextTarget := flag.String("k8s-context-target", "",
+		"kubernetes context associated with the target cluster, for multicluster tests")
+	namespaceTarget := flag.String("linkerd-namespace-target", "linkerd",
+		"the namespace where linkerd is installed on the target cluster, for multicluster tests")
 	verbose := flag.Bool("verbose", false, "turn on debug logging")
 	runTests := flag.Bool("integration-tests", false, "must be provided to run the integration tests")
 
@@ -72,6 +98,7 @@ func NewTestHelper() *TestHelper {
 		linkerd:    *linkerd,
 		namespace:  *namespace,
 		k8sContext: *k8sContext,
+		runner:     execRunner{},
 	}
 
 	rules := clientcmd.NewDefaultClientConfigLoadingRules()
@@ -87,26 +114,238 @@ func NewTestHelper() *TestHelper {
 		exit(1, fmt.Sprintf("error creating serviceprofile clientset: %s", err.Error()))
 	}
 
+	kubeClient, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		exit(1, fmt.Sprintf("error creating kubernetes clientset: %s", err.Error()))
+	}
+
 	kubernetesHelper, err := testutil.NewKubernetesHelper(*k8sContext, testHelper.RetryFor)
 	if err != nil {
 		exit(1, fmt.Sprintf("error creating kubernetes helper: %s", err.Error()))
 	}
 	testHelper.KubernetesHelper = *kubernetesHelper
 	testHelper.spClient = spClient
+	testHelper.kubeClient = kubeClient
+
+	if *k8sContextTarget != "" {
+		targetOverrides := &clientcmd.ConfigOverrides{CurrentContext: *k8sContextTarget}
+		targetKubeConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, targetOverrides)
+		targetConfig, err := targetKubeConfig.ClientConfig()
+		if err != nil {
+			exit(1, fmt.Sprintf("could not read kubernetes config for target context %s: %s", *k8sContextTarget, err.Error()))
+		}
+
+		targetSPClient, err := spclientset.NewForConfig(targetConfig)
+		if err != nil {
+			exit(1, fmt.Sprintf("error creating target serviceprofile clientset: %s", err.Error()))
+		}
+
+		targetK8s, err := testutil.NewKubernetesHelper(*k8sContextTarget, testHelper.RetryFor)
+		if err != nil {
+			exit(1, fmt.Sprintf("error creating target kubernetes helper: %s", err.Error()))
+		}
+
+		testHelper.targetK8sContext = *k8sContextTarget
+		testHelper.targetNamespace = *namespaceTarget
+		testHelper.targetSPClient = targetSPClient
+		testHelper.targetK8s = targetK8s
+	}
 
 	return testHelper
 }
 
-// LinkerdSMIRun executes a linkerd SMI command returning its stdout.
-func (h *TestHelper) LinkerdSMIRun(arg ...string) (string, error) {
-	withParams := append([]string{"smi", "--linkerd-namespace", h.namespace, "--context=" + h.k8sContext}, arg...)
-	out, stderr, err := combinedOutput("", h.linkerd, withParams...)
+// GetLinkerdNamespace returns the namespace where linkerd is installed.
+func (h *TestHelper) GetLinkerdNamespace() string {
+	return h.namespace
+}
+
+// SetCommandRunner overrides the CommandRunner h uses to execute linkerd,
+// kubectl, and docker commands. Tests can use this to inject a FakeRunner,
+// so that TestHelper methods can be exercised without a real cluster or
+// shell.
+func (h *TestHelper) SetCommandRunner(r CommandRunner) {
+	h.runner = r
+}
+
+// SetCommandTimeout bounds every subsequent command h runs to timeout, by
+// wrapping h's current CommandRunner in a TimeoutRunner. This stops a hung
+// external command (e.g. a `linkerd smi install` stuck on a slow admission
+// webhook) from blocking this TestHelper's test run forever. Because the
+// timeout lives on the TestHelper rather than a package-level variable,
+// parallel tests with their own TestHelper do not share or race on it.
+func (h *TestHelper) SetCommandTimeout(timeout time.Duration) {
+	h.runner = TimeoutRunner{Runner: h.runner, Timeout: timeout}
+}
+
+// LinkerdSMIRun executes a linkerd SMI command against the given
+// linkerd-namespace, returning its stdout. The namespace is taken as a
+// parameter, rather than a shared TestHelper field, so that parallel tests
+// can each target their own namespace.
+func (h *TestHelper) LinkerdSMIRun(ns string, arg ...string) (string, error) {
+	withParams := append([]string{"smi", "--linkerd-namespace", ns, "--context=" + h.k8sContext}, arg...)
+	out, stderr, err := h.combinedOutput("", h.linkerd, withParams...)
+	if err != nil {
+		return out, fmt.Errorf("command failed: linkerd smi %s\n%s\n%s", strings.Join(arg, " "), err, stderr)
+	}
+	return out, nil
+}
+
+// UniqueNamespace returns a namespace name of the form <prefix>-<unix
+// timestamp>-<random suffix>, unique to this invocation, for use by
+// parallel tests that each need their own scratch namespace.
+func UniqueNamespace(prefix string) string {
+	return fmt.Sprintf("%s-%d-%s", prefix, time.Now().Unix(), randSuffix(6))
+}
+
+// CleanupWithLogs deletes the test namespace ns, cancelling cancel first if
+// it is non-nil. If the test has already failed, it first dumps the SMI
+// controller logs, TrafficSplit resources, and pod descriptions from ns via
+// t.Log, to aid debugging a parallel run where per-namespace state is
+// otherwise discarded along with the namespace.
+func (h *TestHelper) CleanupWithLogs(t *testing.T, ns string, cancel context.CancelFunc) {
+	if t.Failed() {
+		pods, err := h.GetPods(context.Background(), ns, "")
+		if err != nil {
+			t.Logf("error fetching pods in %s for failure diagnostics: %s", ns, err)
+		}
+
+		for _, pod := range pods {
+			for _, container := range pod.Spec.Containers {
+				logs, err := h.GetContainerLogs(context.Background(), pod, container.Name)
+				if err != nil {
+					t.Logf("error fetching logs for %s/%s: %s", pod.Name, container.Name, err)
+					continue
+				}
+				t.Logf("logs for %s/%s:\n%s", pod.Name, container.Name, logs)
+			}
+
+			desc, _, err := h.combinedOutput("", "kubectl", "--context="+h.k8sContext, "describe", "pod", pod.Name, "-n", ns)
+			if err != nil {
+				t.Logf("error describing pod %s: %s", pod.Name, err)
+				continue
+			}
+			t.Logf("description for pod %s:\n%s", pod.Name, desc)
+		}
+
+		splits, _, err := h.combinedOutput("", "kubectl", "--context="+h.k8sContext, "get", "trafficsplits", "-n", ns, "-o", "yaml")
+		if err != nil {
+			t.Logf("error fetching TrafficSplits in %s: %s", ns, err)
+		} else {
+			t.Logf("TrafficSplits in %s:\n%s", ns, splits)
+		}
+	}
+
+	if cancel != nil {
+		cancel()
+	}
+
+	if _, stderr, err := h.combinedOutput("", "kubectl", "--context="+h.k8sContext, "delete", "namespace", ns, "--ignore-not-found"); err != nil {
+		t.Logf("error deleting namespace %s: %s\n%s", ns, err, stderr)
+	}
+}
+
+// randSuffix returns a random lowercase alphanumeric string of length n, for
+// use in UniqueNamespace.
+func randSuffix(n int) string {
+	const chars = "abcdefghijklmnopqrstuvwxyz0123456789"
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = chars[rand.Intn(len(chars))]
+	}
+	return string(b)
+}
+
+// TargetKubernetesHelper returns the KubernetesHelper for the target cluster
+// used by multicluster TrafficSplit tests. It is nil unless -k8s-context-target
+// was provided.
+func (h *TestHelper) TargetKubernetesHelper() *testutil.KubernetesHelper {
+	return h.targetK8s
+}
+
+// TargetSPClient returns the ServiceProfile clientset for the target cluster
+// used by multicluster TrafficSplit tests. It is nil unless -k8s-context-target
+// was provided.
+func (h *TestHelper) TargetSPClient() *spclientset.Clientset {
+	return h.targetSPClient
+}
+
+// LinkerdSMIRunTarget executes a linkerd SMI command against the given
+// linkerd-namespace on the target cluster used by multicluster TrafficSplit
+// tests, returning its stdout. Like LinkerdSMIRun, the namespace is taken as
+// a parameter, rather than the shared targetNamespace field, so that
+// parallel tests can each target their own namespace on the target cluster.
+func (h *TestHelper) LinkerdSMIRunTarget(ns string, arg ...string) (string, error) {
+	withParams := append([]string{"smi", "--linkerd-namespace", ns, "--context=" + h.targetK8sContext}, arg...)
+	out, stderr, err := h.combinedOutput("", h.linkerd, withParams...)
 	if err != nil {
 		return out, fmt.Errorf("command failed: linkerd smi %s\n%s\n%s", strings.Join(arg, " "), err, stderr)
 	}
 	return out, nil
 }
 
+// FlatNetworkInit flattens the network between two k3d-style clusters so
+// that pods on one can dial pods on the other directly. For each of
+// sourceCtx and targetCtx it discovers the peer's pod CIDR and node IP via
+// `kubectl get nodes`, then installs a route for that CIDR inside the
+// opposing cluster's server container via `docker exec ... ip route add`.
+// This is the network topology SMI TrafficSplit multicluster tests require.
+func (h *TestHelper) FlatNetworkInit(sourceCtx, targetCtx string) error {
+	sourceCIDR, sourceIP, err := h.clusterNetworkInfo(sourceCtx)
+	if err != nil {
+		return fmt.Errorf("error inspecting cluster %s: %w", sourceCtx, err)
+	}
+
+	targetCIDR, targetIP, err := h.clusterNetworkInfo(targetCtx)
+	if err != nil {
+		return fmt.Errorf("error inspecting cluster %s: %w", targetCtx, err)
+	}
+
+	if err := h.addRoute(serverContainer(targetCtx), sourceCIDR, sourceIP); err != nil {
+		return fmt.Errorf("error routing %s into %s: %w", sourceCtx, targetCtx, err)
+	}
+
+	if err := h.addRoute(serverContainer(sourceCtx), targetCIDR, targetIP); err != nil {
+		return fmt.Errorf("error routing %s into %s: %w", targetCtx, sourceCtx, err)
+	}
+
+	return nil
+}
+
+// clusterNetworkInfo returns the pod CIDR and internal node IP of the first
+// node of the cluster identified by k8sContext.
+func (h *TestHelper) clusterNetworkInfo(k8sContext string) (podCIDR, nodeIP string, err error) {
+	podCIDR, stderr, err := h.combinedOutput("", "kubectl", "--context="+k8sContext, "get", "nodes",
+		"-o", "jsonpath={.items[0].spec.podCIDR}")
+	if err != nil {
+		return "", "", fmt.Errorf("%s\n%s", err, stderr)
+	}
+
+	nodeIP, stderr, err = h.combinedOutput("", "kubectl", "--context="+k8sContext, "get", "nodes",
+		"-o", `jsonpath={.items[0].status.addresses[?(@.type=="InternalIP")].address}`)
+	if err != nil {
+		return "", "", fmt.Errorf("%s\n%s", err, stderr)
+	}
+
+	return strings.TrimSpace(podCIDR), strings.TrimSpace(nodeIP), nil
+}
+
+// addRoute installs a route for cidr via viaIP inside the named docker
+// container.
+func (h *TestHelper) addRoute(container, cidr, viaIP string) error {
+	_, stderr, err := h.combinedOutput("", "docker", "exec", container, "ip", "route", "add", cidr, "via", viaIP)
+	if err != nil {
+		return fmt.Errorf("%s\n%s", err, stderr)
+	}
+	return nil
+}
+
+// serverContainer returns the name of the k3d server container backing the
+// cluster associated with the given kubernetes context, following k3d's
+// "<context>-server-0" naming convention.
+func serverContainer(k8sContext string) string {
+	return fmt.Sprintf("%s-server-0", k8sContext)
+}
+
 // GetServiceProfile returns the given ServiceProfile
 func (h *TestHelper) GetServiceProfile(ctx context.Context, namespace, name string) (*serviceprofile.ServiceProfile, error) {
 	sp, err := h.spClient.LinkerdV1alpha2().ServiceProfiles(namespace).Get(ctx, name, metav1.GetOptions{})
@@ -117,6 +356,102 @@ func (h *TestHelper) GetServiceProfile(ctx context.Context, namespace, name stri
 	return sp, nil
 }
 
+// GetPods returns the pods in the given namespace matching labelSelector.
+func (h *TestHelper) GetPods(ctx context.Context, namespace, labelSelector string) ([]corev1.Pod, error) {
+	pods, err := h.kubeClient.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labelSelector,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pods.Items, nil
+}
+
+// GetContainerLogs returns the logs for the given container of the given pod.
+func (h *TestHelper) GetContainerLogs(ctx context.Context, pod corev1.Pod, container string) (string, error) {
+	req := h.kubeClient.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+		Container: container,
+	})
+
+	stream, err := req.Stream(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer stream.Close()
+
+	logs, err := io.ReadAll(stream)
+	if err != nil {
+		return "", err
+	}
+
+	return string(logs), nil
+}
+
+// GetContainerRestartCount returns the restart count reported for the given
+// container (regular or init) of the given pod. It returns an error if the
+// pod has no status for that container.
+func (h *TestHelper) GetContainerRestartCount(pod corev1.Pod, container string) (int32, error) {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name == container {
+			return status.RestartCount, nil
+		}
+	}
+
+	for _, status := range pod.Status.InitContainerStatuses {
+		if status.Name == container {
+			return status.RestartCount, nil
+		}
+	}
+
+	return 0, fmt.Errorf("pod %s has no status for container %s", pod.Name, container)
+}
+
+// CheckPods asserts that the deployment named deployName in namespace ns has
+// the replica count and containers described by spec, and that none of its
+// pods' containers have restarted.
+func (h *TestHelper) CheckPods(ctx context.Context, ns, deployName string, spec DeploySpec) error {
+	deploy, err := h.kubeClient.AppsV1().Deployments(ns).Get(ctx, deployName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("error fetching deployment %s/%s: %w", ns, deployName, err)
+	}
+
+	if int(deploy.Status.ReadyReplicas) != spec.Replicas {
+		return fmt.Errorf("expected %d ready replicas for deployment %s/%s, got %d",
+			spec.Replicas, ns, deployName, deploy.Status.ReadyReplicas)
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(deploy.Spec.Selector)
+	if err != nil {
+		return fmt.Errorf("error parsing selector for deployment %s/%s: %w", ns, deployName, err)
+	}
+
+	pods, err := h.GetPods(ctx, ns, selector.String())
+	if err != nil {
+		return fmt.Errorf("error fetching pods for deployment %s/%s: %w", ns, deployName, err)
+	}
+
+	if len(pods) != spec.Replicas {
+		return fmt.Errorf("expected %d pods for deployment %s/%s, found %d",
+			spec.Replicas, ns, deployName, len(pods))
+	}
+
+	for _, pod := range pods {
+		for _, container := range spec.Containers {
+			restarts, err := h.GetContainerRestartCount(pod, container)
+			if err != nil {
+				return fmt.Errorf("pod %s missing expected container %s: %w", pod.Name, container, err)
+			}
+
+			if restarts != 0 {
+				return fmt.Errorf("container %s of pod %s restarted %d times", container, pod.Name, restarts)
+			}
+		}
+	}
+
+	return nil
+}
+
 // LinkerdRun executes a linkerd command returning its stdout.
 func (h *TestHelper) LinkerdRun(arg ...string) (string, error) {
 	out, stderr, err := h.PipeToLinkerdRun("", arg...)
@@ -130,41 +465,250 @@ func (h *TestHelper) LinkerdRun(arg ...string) (string, error) {
 // --linkerd-namespace flag, and provides a string at Stdin.
 func (h *TestHelper) PipeToLinkerdRun(stdin string, arg ...string) (string, string, error) {
 	withParams := append([]string{"--linkerd-namespace", h.namespace, "--context=" + h.k8sContext}, arg...)
-	return combinedOutput(stdin, h.linkerd, withParams...)
+	return h.combinedOutput(stdin, h.linkerd, withParams...)
 }
 
-// RetryFor retries a given function every second until the function returns
-// without an error, or a timeout is reached. If the timeout is reached, it
-// returns the last error received from the function.
-func (h *TestHelper) RetryFor(timeout time.Duration, fn func() error) error {
+// Backoff determines how long to wait between successive attempts in
+// RetryForBackoff.
+type Backoff interface {
+	// NextBackOff returns the duration to wait before the next attempt.
+	NextBackOff() time.Duration
+	// Reset restores the backoff to its initial state.
+	Reset()
+}
+
+// ExponentialBackoff is a Backoff whose wait grows exponentially between
+// attempts, up to Max, with +/-20% jitter applied to each returned duration
+// to avoid thundering-herd retries.
+type ExponentialBackoff struct {
+	Initial    time.Duration
+	Multiplier float64
+	Max        time.Duration
+
+	current time.Duration
+}
+
+// NewExponentialBackoff returns the ExponentialBackoff used by RetryFor by
+// default: an initial wait of 500ms, a 1.5x multiplier, and a 15s ceiling.
+func NewExponentialBackoff() *ExponentialBackoff {
+	return &ExponentialBackoff{
+		Initial:    500 * time.Millisecond,
+		Multiplier: 1.5,
+		Max:        15 * time.Second,
+	}
+}
+
+// NextBackOff returns the next wait duration, jittered by +/-20%, and
+// advances the backoff towards Max.
+func (b *ExponentialBackoff) NextBackOff() time.Duration {
+	if b.current == 0 {
+		b.current = b.Initial
+	}
+
+	wait := b.current
+
+	next := time.Duration(float64(b.current) * b.Multiplier)
+	if next > b.Max {
+		next = b.Max
+	}
+	b.current = next
+
+	jitter := time.Duration((rand.Float64()*0.4 - 0.2) * float64(wait))
+	return wait + jitter
+}
+
+// Reset restores the backoff to its initial wait duration.
+func (b *ExponentialBackoff) Reset() {
+	b.current = 0
+}
+
+// RetryForBackoff retries fn, waiting b.NextBackOff() between attempts,
+// until fn returns without an error or timeout elapses since the first
+// attempt. If timeout elapses, it returns the last error received from fn.
+func (h *TestHelper) RetryForBackoff(timeout time.Duration, b Backoff, fn func() error) error {
+	b.Reset()
+
 	err := fn()
 	if err == nil {
 		return nil
 	}
 
-	timeoutAfter := time.After(timeout)
-	retryAfter := time.Tick(time.Second)
+	deadline := time.Now().Add(timeout)
 
 	for {
-		select {
-		case <-timeoutAfter:
+		wait := b.NextBackOff()
+		if remaining := time.Until(deadline); wait > remaining {
+			wait = remaining
+		}
+		if wait <= 0 {
+			return err
+		}
+
+		<-time.After(wait)
+
+		err = fn()
+		if err == nil {
+			return nil
+		}
+
+		if !time.Now().Before(deadline) {
 			return err
-		case <-retryAfter:
-			err = fn()
-			if err == nil {
-				return nil
-			}
 		}
 	}
 }
 
-// combinedOutput executes a shell command and returns the output.
-func combinedOutput(stdin string, name string, arg ...string) (string, string, error) {
-	command := exec.Command(name, arg...)
-	command.Stdin = strings.NewReader(stdin)
-	var stderr bytes.Buffer
+// RetryFor retries a given function, backing off exponentially between
+// attempts, until the function returns without an error or a timeout is
+// reached. If the timeout is reached, it returns the last error received
+// from the function.
+//
+// RetryFor is a thin wrapper around RetryForBackoff using the default
+// ExponentialBackoff, kept for source compatibility with existing callers.
+func (h *TestHelper) RetryFor(timeout time.Duration, fn func() error) error {
+	return h.RetryForBackoff(timeout, NewExponentialBackoff(), fn)
+}
+
+// Result captures the outcome of running a command via a CommandRunner.
+type Result struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+	Duration time.Duration
+	Args     []string
+}
+
+// CommandRunner abstracts the execution of external commands, so that
+// higher-level TestHelper methods can be unit-tested against a FakeRunner
+// instead of a real shell, and so that callers can attach per-command
+// timeouts via TimeoutRunner.
+type CommandRunner interface {
+	// Run executes name with the given args and returns its Result.
+	Run(ctx context.Context, name string, args ...string) (Result, error)
+	// RunWithInput executes name with the given args, piping stdin to it,
+	// and returns its Result.
+	RunWithInput(ctx context.Context, stdin io.Reader, name string, args ...string) (Result, error)
+}
+
+// execRunner is the CommandRunner used outside of tests: it shells out via
+// os/exec.
+type execRunner struct{}
+
+// Run implements CommandRunner.
+func (r execRunner) Run(ctx context.Context, name string, args ...string) (Result, error) {
+	return r.RunWithInput(ctx, strings.NewReader(""), name, args...)
+}
+
+// RunWithInput implements CommandRunner.
+func (execRunner) RunWithInput(ctx context.Context, stdin io.Reader, name string, args ...string) (Result, error) {
+	command := exec.CommandContext(ctx, name, args...)
+	command.Stdin = stdin
+	var stdout, stderr bytes.Buffer
+	command.Stdout = &stdout
 	command.Stderr = &stderr
 
-	stdout, err := command.Output()
-	return string(stdout), stderr.String(), err
-}
\ No newline at end of file
+	start := time.Now()
+	err := command.Run()
+
+	result := Result{
+		Stdout:   stdout.String(),
+		Stderr:   stderr.String(),
+		Duration: time.Since(start),
+		Args:     append([]string{name}, args...),
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		result.ExitCode = exitErr.ExitCode()
+	}
+
+	return result, err
+}
+
+// TimeoutRunner wraps a CommandRunner, bounding every invocation to Timeout.
+// This stops a hung external command (e.g. a `linkerd smi install` stuck on
+// a slow admission webhook) from blocking a test run forever.
+type TimeoutRunner struct {
+	Runner  CommandRunner
+	Timeout time.Duration
+}
+
+// Run implements CommandRunner.
+func (r TimeoutRunner) Run(ctx context.Context, name string, args ...string) (Result, error) {
+	return r.RunWithInput(ctx, strings.NewReader(""), name, args...)
+}
+
+// RunWithInput implements CommandRunner.
+func (r TimeoutRunner) RunWithInput(ctx context.Context, stdin io.Reader, name string, args ...string) (Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.Timeout)
+	defer cancel()
+	return r.Runner.RunWithInput(ctx, stdin, name, args...)
+}
+
+// FakeResult is the scripted response for a single FakeRunner invocation.
+type FakeResult struct {
+	Result Result
+	Err    error
+}
+
+// FakeRunner is a CommandRunner that records every invocation and returns a
+// scripted Result, for unit-testing TestHelper methods without a real
+// cluster or shell.
+type FakeRunner struct {
+	// Scripted maps an argv prefix (argv joined by single spaces) to the
+	// FakeResult to return for any call whose argv has that prefix. The
+	// longest matching prefix wins.
+	Scripted map[string]FakeResult
+
+	// Invocations records, in order, the argv of every call made to Run or
+	// RunWithInput.
+	Invocations [][]string
+}
+
+// NewFakeRunner returns an empty FakeRunner, ready to have Scripted entries
+// added to it.
+func NewFakeRunner() *FakeRunner {
+	return &FakeRunner{Scripted: map[string]FakeResult{}}
+}
+
+// Run implements CommandRunner.
+func (f *FakeRunner) Run(ctx context.Context, name string, args ...string) (Result, error) {
+	return f.RunWithInput(ctx, strings.NewReader(""), name, args...)
+}
+
+// RunWithInput implements CommandRunner. It ignores ctx and stdin, records
+// the call, and returns the FakeResult scripted for the longest matching
+// argv prefix. If two distinct scripted prefixes have equal length, which
+// one wins is unspecified; callers should script prefixes that are either
+// disjoint or strictly nested.
+func (f *FakeRunner) RunWithInput(ctx context.Context, stdin io.Reader, name string, args ...string) (Result, error) {
+	argv := append([]string{name}, args...)
+	f.Invocations = append(f.Invocations, argv)
+
+	joined := strings.Join(argv, " ")
+	var bestPrefix string
+	var best FakeResult
+	matched := false
+	for prefix, scripted := range f.Scripted {
+		if !strings.HasPrefix(joined, prefix) {
+			continue
+		}
+		if !matched || len(prefix) > len(bestPrefix) {
+			bestPrefix = prefix
+			best = scripted
+			matched = true
+		}
+	}
+
+	if !matched {
+		return Result{Args: argv}, fmt.Errorf("FakeRunner: no scripted result for %q", joined)
+	}
+
+	result := best.Result
+	result.Args = argv
+	return result, best.Err
+}
+
+// combinedOutput executes a shell command via h.runner and returns its
+// stdout and stderr.
+func (h *TestHelper) combinedOutput(stdin string, name string, arg ...string) (string, string, error) {
+	result, err := h.runner.RunWithInput(context.Background(), strings.NewReader(stdin), name, arg...)
+	return result.Stdout, result.Stderr, err
+}